@@ -0,0 +1,51 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package imagecashletter
+
+import "testing"
+
+// TestEBCDICAlphabet checks every letter against the real IBM037 code page,
+// not just a round trip through this package's own tables. The A-I/J-R/S-Z
+// gaps are different sizes (7 bytes, then 8), and a round trip through
+// toEBCDIC/fromEBCDIC can't catch an encoder/decoder that agree with each
+// other but not with IBM037.
+func TestEBCDICAlphabet(t *testing.T) {
+	upper := []byte{
+		0xC1, 0xC2, 0xC3, 0xC4, 0xC5, 0xC6, 0xC7, 0xC8, 0xC9, // A-I
+		0xD1, 0xD2, 0xD3, 0xD4, 0xD5, 0xD6, 0xD7, 0xD8, 0xD9, // J-R
+		0xE2, 0xE3, 0xE4, 0xE5, 0xE6, 0xE7, 0xE8, 0xE9, // S-Z
+	}
+	lower := []byte{
+		0x81, 0x82, 0x83, 0x84, 0x85, 0x86, 0x87, 0x88, 0x89, // a-i
+		0x91, 0x92, 0x93, 0x94, 0x95, 0x96, 0x97, 0x98, 0x99, // j-r
+		0xA2, 0xA3, 0xA4, 0xA5, 0xA6, 0xA7, 0xA8, 0xA9, // s-z
+	}
+
+	for i := 0; i < 26; i++ {
+		upperLetter := byte('A' + i)
+		if got := asciiToEBCDIC[upperLetter]; got != upper[i] {
+			t.Errorf("asciiToEBCDIC[%q] = 0x%02X, want 0x%02X", upperLetter, got, upper[i])
+		}
+		if got := ebcdicToASCII[upper[i]]; got != upperLetter {
+			t.Errorf("ebcdicToASCII[0x%02X] = %q, want %q", upper[i], got, upperLetter)
+		}
+
+		lowerLetter := byte('a' + i)
+		if got := asciiToEBCDIC[lowerLetter]; got != lower[i] {
+			t.Errorf("asciiToEBCDIC[%q] = 0x%02X, want 0x%02X", lowerLetter, got, lower[i])
+		}
+		if got := ebcdicToASCII[lower[i]]; got != lowerLetter {
+			t.Errorf("ebcdicToASCII[0x%02X] = %q, want %q", lower[i], got, lowerLetter)
+		}
+	}
+}
+
+func TestEBCDICRoundTripFullAlphabet(t *testing.T) {
+	in := []byte("ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz")
+	out := fromEBCDIC(toEBCDIC(in))
+	if string(out) != string(in) {
+		t.Fatalf("round trip: got %q, want %q", out, in)
+	}
+}
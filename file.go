@@ -41,6 +41,10 @@ const (
 	routingNumberSummaryPos = "85"
 	cashLetterControlPos    = "90"
 	fileControlPos          = "99"
+	// userDefinedSignaturePos is a moov-io extension (not part of the
+	// X9.100-187 standard) carrying a detached X9.100-180 style
+	// signature over the file's canonical bytes. See Sign/Verify.
+	userDefinedSignaturePos = "68"
 	// no longer supported by the standard
 	// accountTotalsDetailPos  = "40"
 	// nonHitTotalsDetailPos   = "41"
@@ -99,6 +103,9 @@ type File struct {
 	Bundles []Bundle `json:"bundle,omitempty"`
 	// FileControl is an imagecashletter FileControl
 	Control FileControl `json:"fileControl"`
+	// Signature is a detached X9.100-180 style signature over this
+	// File's canonical bytes, set by Sign and checked by Verify.
+	Signature *Signature `json:"signature,omitempty"`
 }
 
 // NewFile constructs a file template with a FileHeader and FileControl.
@@ -167,7 +174,10 @@ func FileFromJSON(bs []byte) (*File, error) {
 	return file, nil
 }
 
-// Create creates a valid imagecashletter File
+// Create computes f's FileControl from the CashLetters/Bundles already
+// present on f. It does not serialize anything: Write calls Create itself
+// before streaming through NewWriter/WriteFile, so there is one "build
+// totals, then serialize" implementation rather than a separate bulk one.
 func (f *File) Create() error {
 	if f == nil {
 		return ErrNilFile
@@ -250,15 +260,13 @@ func (f *File) Create() error {
 	return nil
 }
 
-// Validate validates an ICL File
+// Validate validates an ICL File. It returns the first error found; use
+// ValidateAll to see everything wrong with a file in one pass.
 func (f *File) Validate() error {
 	if f == nil {
 		return ErrNilFile
 	}
-	if err := f.CashLetterIDUnique(); err != nil {
-		return err
-	}
-	return nil
+	return f.ValidateAll().FirstError()
 }
 
 // SetHeader allows for header to be built.
@@ -0,0 +1,113 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package imagecashletter
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// recordFramer is the pluggable boundary between the logical record stream
+// (FileHeader, CashLetterHeader, CheckDetail, ...) and the bytes a
+// particular exchange partner puts on the wire. Selecting an Encoding in
+// ReaderOptions/WriterOptions picks one of these without the caller
+// needing to know about record boundaries.
+type recordFramer interface {
+	// next returns the raw, ASCII bytes of the next record. The
+	// fixed-width framers strip the trailing space padding; the
+	// variable-length framer returns the record as declared. It returns
+	// io.EOF once the stream is exhausted.
+	next(r *bufio.Reader) ([]byte, error)
+	// write frames record (ASCII bytes, no trailing newline) and writes
+	// it to w.
+	write(w io.Writer, record []byte) error
+}
+
+// newRecordFramer selects a recordFramer for the given Encoding.
+// maxRecordLength bounds VariableLengthEncoding's length prefix and is
+// ignored by the fixed-width framers.
+func newRecordFramer(enc Encoding, maxRecordLength uint32) (recordFramer, error) {
+	switch enc {
+	case ASCIIEncoding:
+		return &fixedWidthFramer{}, nil
+	case EBCDICEncoding:
+		return &fixedWidthFramer{ebcdic: true}, nil
+	case VariableLengthEncoding:
+		return &variableLengthFramer{maxRecordLength: maxRecordLength}, nil
+	default:
+		return nil, fmt.Errorf("imagecashletter: unknown Encoding %d", enc)
+	}
+}
+
+// fixedWidthFramer reads/writes 80 byte fixed-length records, the layout
+// used by ASCII and EBCDIC (FRB) X9.37 exchange.
+type fixedWidthFramer struct {
+	ebcdic bool
+}
+
+func (f *fixedWidthFramer) next(r *bufio.Reader) ([]byte, error) {
+	raw := make([]byte, fixedRecordLength)
+	if n, err := io.ReadFull(r, raw); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			return nil, fmt.Errorf("imagecashletter: "+msgRecordLength, n)
+		}
+		return nil, err
+	}
+	if f.ebcdic {
+		raw = fromEBCDIC(raw)
+	}
+	return bytes.TrimRight(raw, " "), nil
+}
+
+func (f *fixedWidthFramer) write(w io.Writer, record []byte) error {
+	if len(record) > fixedRecordLength {
+		return fmt.Errorf("imagecashletter: record is %d bytes, longer than the %d byte fixed-width record length", len(record), fixedRecordLength)
+	}
+	out := make([]byte, fixedRecordLength)
+	copy(out, record)
+	for i := len(record); i < fixedRecordLength; i++ {
+		out[i] = ' '
+	}
+	if f.ebcdic {
+		out = toEBCDIC(out)
+	}
+	_, err := w.Write(out)
+	return err
+}
+
+// variableLengthFramer implements the DSTU microformat, which prefixes each
+// record with its length as a 4 byte big endian integer.
+type variableLengthFramer struct {
+	maxRecordLength uint32
+}
+
+func (f *variableLengthFramer) next(r *bufio.Reader) ([]byte, error) {
+	var length uint32
+	if err := binary.Read(r, binary.BigEndian, &length); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			return nil, fmt.Errorf("imagecashletter: truncated DSTU length prefix")
+		}
+		return nil, err
+	}
+	if length > f.maxRecordLength {
+		return nil, fmt.Errorf("imagecashletter: DSTU record length %d exceeds the %d byte maximum", length, f.maxRecordLength)
+	}
+	raw := make([]byte, length)
+	if n, err := io.ReadFull(r, raw); err != nil {
+		return nil, fmt.Errorf("imagecashletter: truncated DSTU record: length prefix declares %d bytes and found %d", length, n)
+	}
+	return raw, nil
+}
+
+func (f *variableLengthFramer) write(w io.Writer, record []byte) error {
+	if err := binary.Write(w, binary.BigEndian, uint32(len(record))); err != nil {
+		return err
+	}
+	_, err := w.Write(record)
+	return err
+}
@@ -0,0 +1,95 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package imagecashletter
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestFixedWidthFramer_RoundTrip(t *testing.T) {
+	for _, ebcdic := range []bool{false, true} {
+		f := &fixedWidthFramer{ebcdic: ebcdic}
+
+		var buf bytes.Buffer
+		if err := f.write(&buf, []byte("01hello")); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+		if buf.Len() != fixedRecordLength {
+			t.Fatalf("wrote %d bytes, want %d", buf.Len(), fixedRecordLength)
+		}
+
+		got, err := f.next(bufio.NewReader(&buf))
+		if err != nil {
+			t.Fatalf("next: %v", err)
+		}
+		if string(got) != "01hello" {
+			t.Fatalf("got %q, want %q", got, "01hello")
+		}
+	}
+}
+
+func TestFixedWidthFramer_RejectsOversizedRecord(t *testing.T) {
+	f := &fixedWidthFramer{}
+	oversized := strings.Repeat("x", fixedRecordLength+1)
+
+	var buf bytes.Buffer
+	if err := f.write(&buf, []byte(oversized)); err == nil {
+		t.Fatalf("expected write to reject a record longer than %d bytes", fixedRecordLength)
+	}
+}
+
+func TestFixedWidthFramer_TruncatedRecord(t *testing.T) {
+	f := &fixedWidthFramer{}
+	_, err := f.next(bufio.NewReader(bytes.NewReader([]byte("short"))))
+	if err == nil {
+		t.Fatalf("expected error reading a truncated fixed-width record")
+	}
+}
+
+func TestVariableLengthFramer_RoundTrip(t *testing.T) {
+	f := &variableLengthFramer{maxRecordLength: defaultMaxRecordLength}
+
+	var buf bytes.Buffer
+	if err := f.write(&buf, []byte("68somesignature")); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	got, err := f.next(bufio.NewReader(&buf))
+	if err != nil {
+		t.Fatalf("next: %v", err)
+	}
+	if string(got) != "68somesignature" {
+		t.Fatalf("got %q, want %q", got, "68somesignature")
+	}
+}
+
+func TestVariableLengthFramer_RejectsOversizedLengthPrefix(t *testing.T) {
+	f := &variableLengthFramer{maxRecordLength: 80}
+
+	// A length prefix claiming a 1GB record, with none of that data
+	// actually present. Without a cap this allocates 1GB before
+	// noticing the stream is short.
+	var buf bytes.Buffer
+	buf.Write([]byte{0x3B, 0x9A, 0xCA, 0x00}) // 1,000,000,000 big-endian
+
+	_, err := f.next(bufio.NewReader(&buf))
+	if err == nil {
+		t.Fatalf("expected error for a length prefix exceeding maxRecordLength")
+	}
+}
+
+func TestVariableLengthFramer_TruncatedRecord(t *testing.T) {
+	f := &variableLengthFramer{maxRecordLength: defaultMaxRecordLength}
+
+	var buf bytes.Buffer
+	buf.Write([]byte{0x00, 0x00, 0x00, 0x0A}) // declares 10 bytes, writes none
+	_, err := f.next(bufio.NewReader(&buf))
+	if err == nil {
+		t.Fatalf("expected error reading a truncated variable-length record")
+	}
+}
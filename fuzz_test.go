@@ -0,0 +1,93 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package imagecashletter
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// fixedRecord pads s out to the 80 byte fixed-width record length used by
+// ASCIIEncoding, mirroring what a real FRB exchange partner would send.
+func fixedRecord(s string) string {
+	if len(s) >= fixedRecordLength {
+		return s[:fixedRecordLength]
+	}
+	return s + strings.Repeat(" ", fixedRecordLength-len(s))
+}
+
+// FuzzReader feeds arbitrary bytes into the streaming Reader under every
+// Encoding this package supports, asserting that malformed input never
+// panics and that a *File which Create, the streaming Writer and a second
+// read all accept keeps its byte length when written a second time.
+// Inputs rejected at any of those steps end the case without failing it.
+func FuzzReader(f *testing.F) {
+	encodings := []Encoding{ASCIIEncoding, EBCDICEncoding, VariableLengthEncoding}
+
+	for i := range encodings {
+		f.Add(byte(i), []byte(fixedRecord(fileHeaderPos)+fixedRecord(fileControlPos)))
+		f.Add(byte(i), []byte(fixedRecord("")))
+		f.Add(byte(i), []byte{})
+		f.Add(byte(i), []byte(fixedRecord(fileHeaderPos)))
+	}
+
+	f.Fuzz(func(t *testing.T, encSelect byte, data []byte) {
+		enc := encodings[int(encSelect)%len(encodings)]
+
+		file, err := NewFileFromReader(bytes.NewReader(data), ReaderOptions{Format: Discover, Encoding: enc})
+		if err != nil {
+			return
+		}
+		if err := file.Create(); err != nil {
+			return
+		}
+
+		var buf bytes.Buffer
+		if err := file.Write(&buf, WriterOptions{Format: Discover, Encoding: enc}); err != nil {
+			return
+		}
+
+		var roundTrip bytes.Buffer
+		rt, err := NewFileFromReader(bytes.NewReader(buf.Bytes()), ReaderOptions{Format: Discover, Encoding: enc})
+		if err != nil {
+			return
+		}
+		if err := rt.Create(); err != nil {
+			return
+		}
+		if err := rt.Write(&roundTrip, WriterOptions{Format: Discover, Encoding: enc}); err != nil {
+			return
+		}
+		if roundTrip.Len() != buf.Len() {
+			t.Fatalf("round-trip changed length: %d vs %d", buf.Len(), roundTrip.Len())
+		}
+	})
+}
+
+// FuzzFileFromJSON feeds arbitrary bytes into FileFromJSON and passes any
+// returned *File through Create and the streaming Writer, asserting only
+// that nothing panics: an error from any of those steps ends the case
+// without failing it, and the written byte length is not compared.
+func FuzzFileFromJSON(f *testing.F) {
+	f.Add([]byte(`{}`))
+	f.Add([]byte(`{"id":"1"}`))
+	f.Add([]byte(`{"fileHeader":{},"fileControl":{}}`))
+	f.Add([]byte(``))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		file, err := FileFromJSON(data)
+		if err != nil {
+			return
+		}
+		if err := file.Create(); err != nil {
+			return
+		}
+		var buf bytes.Buffer
+		if err := file.Write(&buf, WriterOptions{Format: Discover, Encoding: ASCIIEncoding}); err != nil {
+			return
+		}
+	})
+}
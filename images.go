@@ -0,0 +1,69 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package imagecashletter
+
+import (
+	"fmt"
+
+	"github.com/moov-io/imagecashletter/imagesupport"
+)
+
+// ValidateImages walks every check and return in f and validates each
+// ImageViewData's embedded TIFF against the ANSI TS X9.100-181 profile
+// Check 21 requires (bitonal Group 4, 200 DPI, consistent strip layout),
+// cross-checking front/back pairs for consistency. It returns one error
+// per image that fails, rather than stopping at the first problem, so
+// callers building files from scanner output can fix everything in one
+// pass.
+func (f *File) ValidateImages() []error {
+	if f == nil {
+		return []error{ErrNilFile}
+	}
+
+	var errs []error
+	for i, cl := range f.CashLetters {
+		for j, b := range cl.Bundles {
+			for k, cd := range b.Checks {
+				path := fmt.Sprintf("cashLetters[%d].bundles[%d].checks[%d]", i, j, k)
+				errs = append(errs, validateImageViews(path, cd.ImageViewDetail, cd.ImageViewData)...)
+			}
+			for k, rd := range b.Returns {
+				path := fmt.Sprintf("cashLetters[%d].bundles[%d].returns[%d]", i, j, k)
+				errs = append(errs, validateImageViews(path, rd.ImageViewDetail, rd.ImageViewData)...)
+			}
+		}
+	}
+	return errs
+}
+
+func validateImageViews(path string, details []ImageViewDetail, datas []ImageViewData) []error {
+	var errs []error
+	var front, back []byte
+
+	for i, data := range datas {
+		if i >= len(details) {
+			errs = append(errs, fmt.Errorf("%s.ImageViewData[%d]: no matching ImageViewDetail", path, i))
+			continue
+		}
+		side := imagesupport.ViewSide(details[i].ViewSide)
+		if err := imagesupport.Validate(data.ImageData, side, imagesupport.DefaultProfile); err != nil {
+			errs = append(errs, fmt.Errorf("%s.ImageViewData[%d]: %v", path, i, err))
+			continue
+		}
+		switch side {
+		case imagesupport.FrontSide:
+			front = data.ImageData
+		case imagesupport.BackSide:
+			back = data.ImageData
+		}
+	}
+
+	if front != nil && back != nil {
+		if err := imagesupport.ValidateFrontBackConsistency(front, back); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %v", path, err))
+		}
+	}
+	return errs
+}
@@ -0,0 +1,319 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package imagesupport
+
+// ccittg4.go implements CCITT Group 4 (ITU-T T.6) two-dimensional encoding,
+// the compression scheme X9.100-181 requires (TIFF Compression tag 4).
+//
+// T.6 encodes each row relative to the row above it (the "reference line")
+// as a sequence of changing elements (column positions where white/black
+// flips), using one of three modes:
+//
+//   - Pass: the reference line's next opposite-color run ends before the
+//     coding line's next changing element; absorb it and keep going.
+//   - Vertical: the coding line's next changing element is within 3 columns
+//     of the reference line's; code the small offset directly (V0/VL/VR).
+//   - Horizontal: neither of the above applies; code the two run lengths
+//     (current color, then the opposite color) with Modified Huffman codes.
+//
+// There is no end-of-line code between rows (T.6, unlike T.4, doesn't use
+// one) and no fill bits; rows are packed back-to-back and only the final
+// byte of the strip is padded.
+
+// elementColor is a changing element's color using this package's convention:
+// 1 = white, 0 = black (matching thresholdToBitonal's packed bits).
+type elementColor byte
+
+const (
+	black elementColor = 0
+	white elementColor = 1
+)
+
+// bitWriter packs bits MSB-first into a byte slice, the bit order TIFF's
+// default FillOrder (1) requires.
+type bitWriter struct {
+	out   []byte
+	cur   byte
+	nbits uint
+}
+
+func (w *bitWriter) writeBits(value uint32, nbits int) {
+	for i := nbits - 1; i >= 0; i-- {
+		w.cur = w.cur<<1 | byte((value>>uint(i))&1)
+		w.nbits++
+		if w.nbits == 8 {
+			w.out = append(w.out, w.cur)
+			w.cur = 0
+			w.nbits = 0
+		}
+	}
+}
+
+// flush pads the final partial byte with zero bits, as required to land on
+// a byte boundary at the end of a strip.
+func (w *bitWriter) flush() []byte {
+	if w.nbits > 0 {
+		w.cur <<= 8 - w.nbits
+		w.out = append(w.out, w.cur)
+		w.cur = 0
+		w.nbits = 0
+	}
+	return w.out
+}
+
+// mhCode is one entry of a Modified Huffman run-length code table.
+type mhCode struct {
+	bits  uint32
+	nbits int
+}
+
+// whiteCodes and blackCodes are indexed by run length. Only entries that
+// are actually used (multiples of 64 above 63, and every length 0-63) are
+// populated. writeRun codes lengths of 2560 and above as repeated 2560
+// makeup codes, then makeup codes of at most 1728 for any remainder of 64
+// or more, then a terminating code.
+var whiteCodes = buildMHTable(map[int]mhCode{
+	0: {0x35, 8}, 1: {0x07, 6}, 2: {0x07, 4}, 3: {0x08, 4},
+	4: {0x0B, 4}, 5: {0x0C, 4}, 6: {0x0E, 4}, 7: {0x0F, 4},
+	8: {0x13, 5}, 9: {0x14, 5}, 10: {0x07, 5}, 11: {0x08, 5},
+	12: {0x08, 6}, 13: {0x03, 6}, 14: {0x34, 6}, 15: {0x35, 6},
+	16: {0x2A, 6}, 17: {0x2B, 6}, 18: {0x27, 7}, 19: {0x0C, 7},
+	20: {0x08, 7}, 21: {0x17, 7}, 22: {0x03, 7}, 23: {0x04, 7},
+	24: {0x28, 7}, 25: {0x2B, 7}, 26: {0x13, 7}, 27: {0x24, 7},
+	28: {0x18, 7}, 29: {0x02, 8}, 30: {0x03, 8}, 31: {0x1A, 8},
+	32: {0x1B, 8}, 33: {0x12, 8}, 34: {0x13, 8}, 35: {0x14, 8},
+	36: {0x15, 8}, 37: {0x16, 8}, 38: {0x17, 8}, 39: {0x28, 8},
+	40: {0x29, 8}, 41: {0x2A, 8}, 42: {0x2B, 8}, 43: {0x2C, 8},
+	44: {0x2D, 8}, 45: {0x04, 8}, 46: {0x05, 8}, 47: {0x0A, 8},
+	48: {0x0B, 8}, 49: {0x52, 8}, 50: {0x53, 8}, 51: {0x54, 8},
+	52: {0x55, 8}, 53: {0x24, 8}, 54: {0x25, 8}, 55: {0x58, 8},
+	56: {0x59, 8}, 57: {0x5A, 8}, 58: {0x5B, 8}, 59: {0x4A, 8},
+	60: {0x4B, 8}, 61: {0x4C, 8}, 62: {0x4D, 8}, 63: {0x32, 8},
+
+	64: {0x1B, 5}, 128: {0x12, 5}, 192: {0x17, 6}, 256: {0x37, 7},
+	320: {0x36, 8}, 384: {0x37, 8}, 448: {0x64, 8}, 512: {0x65, 8},
+	576: {0x68, 8}, 640: {0x67, 8}, 704: {0xCC, 9}, 768: {0xCD, 9},
+	832: {0xD2, 9}, 896: {0xD3, 9}, 960: {0xD4, 9}, 1024: {0xD5, 9},
+	1088: {0xD6, 9}, 1152: {0xD7, 9}, 1216: {0xD8, 9}, 1280: {0xD9, 9},
+	1344: {0xDA, 9}, 1408: {0xDB, 9}, 1472: {0x98, 9}, 1536: {0x99, 9},
+	1600: {0x9A, 9}, 1664: {0x18, 6}, 1728: {0x9B, 9},
+}, extendedMakeupCodes)
+
+var blackCodes = buildMHTable(map[int]mhCode{
+	0: {0x37, 10}, 1: {0x02, 3}, 2: {0x03, 2}, 3: {0x02, 2},
+	4: {0x03, 3}, 5: {0x03, 4}, 6: {0x02, 4}, 7: {0x03, 5},
+	8: {0x05, 6}, 9: {0x04, 6}, 10: {0x04, 7}, 11: {0x05, 7},
+	12: {0x07, 7}, 13: {0x04, 8}, 14: {0x07, 8}, 15: {0x18, 9},
+	16: {0x17, 10}, 17: {0x18, 10}, 18: {0x08, 10}, 19: {0x67, 11},
+	20: {0x68, 11}, 21: {0x6C, 11}, 22: {0x37, 11}, 23: {0x28, 11},
+	24: {0x17, 11}, 25: {0x18, 11}, 26: {0xCA, 12}, 27: {0xCB, 12},
+	28: {0xCC, 12}, 29: {0xCD, 12}, 30: {0x68, 12}, 31: {0x69, 12},
+	32: {0x6A, 12}, 33: {0x6B, 12}, 34: {0xD2, 12}, 35: {0xD3, 12},
+	36: {0xD4, 12}, 37: {0xD5, 12}, 38: {0xD6, 12}, 39: {0xD7, 12},
+	40: {0x6C, 12}, 41: {0x6D, 12}, 42: {0xDA, 12}, 43: {0xDB, 12},
+	44: {0x54, 12}, 45: {0x55, 12}, 46: {0x56, 12}, 47: {0x57, 12},
+	48: {0x64, 12}, 49: {0x65, 12}, 50: {0x52, 12}, 51: {0x53, 12},
+	52: {0x24, 12}, 53: {0x37, 12}, 54: {0x38, 12}, 55: {0x27, 12},
+	56: {0x28, 12}, 57: {0x58, 12}, 58: {0x59, 12}, 59: {0x2B, 12},
+	60: {0x2C, 12}, 61: {0x5A, 12}, 62: {0x66, 12}, 63: {0x67, 12},
+
+	64: {0x0F, 10}, 128: {0xC8, 12}, 192: {0xC9, 12}, 256: {0x5B, 12},
+	320: {0x33, 12}, 384: {0x34, 12}, 448: {0x35, 12}, 512: {0x6C, 13},
+	576: {0x6D, 13}, 640: {0x4A, 13}, 704: {0x4B, 13}, 768: {0x4C, 13},
+	832: {0x4D, 13}, 896: {0x72, 13}, 960: {0x73, 13}, 1024: {0x74, 13},
+	1088: {0x75, 13}, 1152: {0x76, 13}, 1216: {0x77, 13}, 1280: {0x52, 13},
+	1344: {0x53, 13}, 1408: {0x54, 13}, 1472: {0x55, 13}, 1536: {0x5A, 13},
+	1600: {0x5B, 13}, 1664: {0x64, 13}, 1728: {0x65, 13},
+}, extendedMakeupCodes)
+
+// extendedMakeupCodes are makeup codes shared by both colors for runs of
+// 1792 and above (T.4 Table 3).
+var extendedMakeupCodes = map[int]mhCode{
+	1792: {0x08, 11}, 1856: {0x0C, 11}, 1920: {0x0D, 11},
+	1984: {0x12, 12}, 2048: {0x13, 12}, 2112: {0x14, 12},
+	2176: {0x15, 12}, 2240: {0x16, 12}, 2304: {0x17, 12},
+	2368: {0x1C, 12}, 2432: {0x1D, 12}, 2496: {0x1E, 12}, 2560: {0x1F, 12},
+}
+
+func buildMHTable(base, extended map[int]mhCode) map[int]mhCode {
+	t := make(map[int]mhCode, len(base)+len(extended))
+	for k, v := range base {
+		t[k] = v
+	}
+	for k, v := range extended {
+		t[k] = v
+	}
+	return t
+}
+
+// writeRun emits run, a count of same-colored pixels, as zero or more
+// 2560-run makeup codes, then makeup codes of at most 1728 while 64 or
+// more pixels remain, then a single terminating code (0-63).
+func writeRun(w *bitWriter, c elementColor, run int) {
+	codes := whiteCodes
+	if c == black {
+		codes = blackCodes
+	}
+	for run >= 2560 {
+		mc := extendedMakeupCodes[2560]
+		w.writeBits(mc.bits, mc.nbits)
+		run -= 2560
+	}
+	for run >= 64 {
+		makeup := (run / 64) * 64
+		if makeup > 1728 {
+			makeup = 1728
+		}
+		mc := codes[makeup]
+		w.writeBits(mc.bits, mc.nbits)
+		run -= makeup
+	}
+	mc := codes[run]
+	w.writeBits(mc.bits, mc.nbits)
+}
+
+const (
+	modePass             = 0x1 // 0001
+	modePassBits         = 4
+	modeHorizontal       = 0x1 // 001
+	modeHorizontalBits   = 3
+	modeV0               = 0x1 // 1
+	modeV0Bits           = 1
+	modeVR1, modeVR1Bits = 0x3, 3 // 011
+	modeVR2, modeVR2Bits = 0x3, 6 // 000011
+	modeVR3, modeVR3Bits = 0x3, 7 // 0000011
+	modeVL1, modeVL1Bits = 0x2, 3 // 010
+	modeVL2, modeVL2Bits = 0x2, 6 // 000010
+	modeVL3, modeVL3Bits = 0x2, 7 // 0000010
+)
+
+// changingElements returns the column of every color change in row (an
+// MSB-first packed bitonal row, 1 = white), assuming an imaginary white
+// element precedes column 0. Two width-valued sentinels are appended so
+// b1/b2/a1/a2 lookups never run off the end of the slice.
+func changingElements(row []byte, width int) []int {
+	var changes []int
+	prev := white
+	for x := 0; x < width; x++ {
+		bit := elementColor((row[x/8] >> uint(7-x%8)) & 1)
+		if bit != prev {
+			changes = append(changes, x)
+			prev = bit
+		}
+	}
+	return append(changes, width, width)
+}
+
+// colorAt returns the color of the changes[i]'th changing element: the
+// first change in a row is always a transition to black (since the line
+// starts white), so color alternates starting from black at index 0.
+func colorAt(i int) elementColor {
+	if i%2 == 0 {
+		return black
+	}
+	return white
+}
+
+// findB1 returns the index into ref of b1: the first changing element to
+// the right of a0 whose color is the opposite of cur.
+func findB1(ref []int, a0 int, cur elementColor) int {
+	i := 0
+	for i < len(ref)-1 && ref[i] <= a0 {
+		i++
+	}
+	if colorAt(i) == cur {
+		i++
+	}
+	return i
+}
+
+// packG4Encode 2D-encodes height rows of width-wide packed bitonal pixels
+// (as produced by thresholdToBitonal) using CCITT Group 4 (T.6).
+func packG4Encode(rows []byte, width, height int) []byte {
+	stride := (width + 7) / 8
+	w := &bitWriter{}
+
+	ref := []int{width, width} // imaginary all-white line above row 0
+	for y := 0; y < height; y++ {
+		cur := changingElements(rows[y*stride:(y+1)*stride], width)
+		encodeRow(w, ref, cur, width)
+		ref = cur
+	}
+	return w.flush()
+}
+
+func encodeRow(w *bitWriter, ref, cur []int, width int) {
+	a0 := -1
+	c := white
+
+	for a0 < width {
+		bi := findB1(ref, a0, c)
+		b1, b2 := ref[bi], ref[min(bi+1, len(ref)-1)]
+
+		ai := 0
+		for ai < len(cur)-1 && cur[ai] <= a0 {
+			ai++
+		}
+		a1, a2 := cur[ai], cur[min(ai+1, len(cur)-1)]
+
+		switch {
+		case b2 < a1:
+			w.writeBits(modePass, modePassBits)
+			a0 = b2
+			// color unchanged
+		case abs(a1-b1) <= 3:
+			writeVertical(w, a1-b1)
+			a0 = a1
+			c = 1 - c
+		default:
+			w.writeBits(modeHorizontal, modeHorizontalBits)
+			run1 := a1 - max(a0, 0)
+			writeRun(w, c, run1)
+			writeRun(w, 1-c, a2-a1)
+			a0 = a2
+			// color unchanged
+		}
+	}
+}
+
+func writeVertical(w *bitWriter, delta int) {
+	switch delta {
+	case 0:
+		w.writeBits(modeV0, modeV0Bits)
+	case 1:
+		w.writeBits(modeVR1, modeVR1Bits)
+	case 2:
+		w.writeBits(modeVR2, modeVR2Bits)
+	case 3:
+		w.writeBits(modeVR3, modeVR3Bits)
+	case -1:
+		w.writeBits(modeVL1, modeVL1Bits)
+	case -2:
+		w.writeBits(modeVL2, modeVL2Bits)
+	case -3:
+		w.writeBits(modeVL3, modeVL3Bits)
+	}
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
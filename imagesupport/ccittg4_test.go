@@ -0,0 +1,239 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package imagesupport
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+)
+
+// TestPackG4Encode_SingleRowMatchesHandTrace checks the encoder's bit
+// output against a trace worked by hand against the T.6 algorithm and
+// Modified Huffman tables, independent of the decoder below, so a bug
+// shared between encoder and decoder can't hide from both checks.
+//
+// Row: WWWWBBBB (8 pixels) against an all-white reference line.
+//
+//	a0=-1, color=white; b1=8 (sentinel), b2=8; a1=4, a2=8.
+//	b2(8) is not < a1(4), so not Pass. delta=a1-b1=4-8=-4, |delta|>3, so
+//	Horizontal: run1 = a1-0 = 4 white (code "1011"), run2 = a2-a1 = 4
+//	black (code "011"). Codeword = "001" + "1011" + "011" = "0011011011",
+//	padded to two bytes: 00110110 11000000 = 0x36 0xC0.
+func TestPackG4Encode_SingleRowMatchesHandTrace(t *testing.T) {
+	row := []byte{0xF0} // 11110000: 4 white pixels, then 4 black
+	got := packG4Encode(row, 8, 1)
+	want := []byte{0x36, 0xC0}
+	if !bytes.Equal(got, want) {
+		t.Fatalf("got % X, want % X", got, want)
+	}
+}
+
+func TestPackG4EncodeDecodeRoundTrip(t *testing.T) {
+	cases := []struct {
+		name          string
+		width, height int
+		bit           func(x, y int) bool // true = white
+	}{
+		{"all white", 16, 8, func(x, y int) bool { return true }},
+		{"all black", 16, 8, func(x, y int) bool { return false }},
+		{"checkerboard", 16, 8, func(x, y int) bool { return (x+y)%2 == 0 }},
+		{"vertical stripes", 17, 5, func(x, y int) bool { return (x/3)%2 == 0 }},
+		{"horizontal bands", 33, 9, func(x, y int) bool { return (y/2)%2 == 0 }},
+		{"single black pixel", 10, 3, func(x, y int) bool { return !(x == 5 && y == 1) }},
+		{"alternating pixels", 23, 4, func(x, y int) bool { return x%2 == 0 }},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			stride := (tc.width + 7) / 8
+			rows := make([]byte, stride*tc.height)
+			for y := 0; y < tc.height; y++ {
+				for x := 0; x < tc.width; x++ {
+					if tc.bit(x, y) {
+						rows[y*stride+x/8] |= 1 << uint(7-x%8)
+					}
+				}
+			}
+
+			encoded := packG4Encode(rows, tc.width, tc.height)
+			decoded := decodeG4(t, encoded, tc.width, tc.height)
+			if !bytes.Equal(decoded, rows) {
+				t.Fatalf("round trip mismatch:\n got  % X\n want % X", decoded, rows)
+			}
+		})
+	}
+}
+
+// --- minimal G4 decoder, used only to verify the encoder in tests ---
+
+type bitReader struct {
+	data []byte
+	pos  int // bit position
+}
+
+func (r *bitReader) readBit() (int, error) {
+	if r.pos >= len(r.data)*8 {
+		return 0, fmt.Errorf("read past end of stream")
+	}
+	b := (r.data[r.pos/8] >> uint(7-r.pos%8)) & 1
+	r.pos++
+	return int(b), nil
+}
+
+var whiteDecodeTable, blackDecodeTable = buildDecodeTables()
+
+func buildDecodeTables() (map[int]map[uint32]int, map[int]map[uint32]int) {
+	build := func(codes map[int]mhCode) map[int]map[uint32]int {
+		t := make(map[int]map[uint32]int)
+		for run, c := range codes {
+			if t[c.nbits] == nil {
+				t[c.nbits] = make(map[uint32]int)
+			}
+			t[c.nbits][c.bits] = run
+		}
+		return t
+	}
+	return build(whiteCodes), build(blackCodes)
+}
+
+func readRun(t *testing.T, r *bitReader, c elementColor) int {
+	t.Helper()
+	table := whiteDecodeTable
+	if c == black {
+		table = blackDecodeTable
+	}
+
+	total := 0
+	for {
+		var code uint32
+		var nbits int
+		run := -1
+		for nbits < 14 {
+			bit, err := r.readBit()
+			if err != nil {
+				t.Fatalf("readRun: %v", err)
+			}
+			code = code<<1 | uint32(bit)
+			nbits++
+			if m, ok := table[nbits]; ok {
+				if v, ok := m[code]; ok {
+					run = v
+					break
+				}
+			}
+		}
+		if run < 0 {
+			t.Fatalf("readRun: no matching code after %d bits", nbits)
+		}
+		total += run
+		if run < 64 {
+			return total
+		}
+	}
+}
+
+// decodeG4 decodes a T.6 bitstream produced by packG4Encode back into
+// packed bitonal rows, mirroring encodeRow's mode selection in reverse.
+func decodeG4(t *testing.T, data []byte, width, height int) []byte {
+	t.Helper()
+	stride := (width + 7) / 8
+	out := make([]byte, stride*height)
+	r := &bitReader{data: data}
+
+	ref := []int{width, width}
+	for y := 0; y < height; y++ {
+		cur := decodeRow(t, r, ref, width)
+		// paint cur's alternating white/black runs into out
+		c := white
+		pos := 0
+		for _, x := range cur {
+			if x > width {
+				x = width
+			}
+			if c == white {
+				for px := pos; px < x; px++ {
+					out[y*stride+px/8] |= 1 << uint(7-px%8)
+				}
+			}
+			pos = x
+			c = 1 - c
+			if pos >= width {
+				break
+			}
+		}
+		ref = cur
+	}
+	return out
+}
+
+func decodeRow(t *testing.T, r *bitReader, ref []int, width int) []int {
+	t.Helper()
+	a0 := -1
+	c := white
+	var cur []int
+
+	for a0 < width {
+		bi := findB1(ref, a0, c)
+		b1, b2 := ref[bi], ref[min(bi+1, len(ref)-1)]
+
+		mode, delta := readMode(t, r)
+		switch mode {
+		case "pass":
+			a0 = b2
+		case "horizontal":
+			run1 := readRun(t, r, c)
+			run2 := readRun(t, r, 1-c)
+			a1 := max(a0, 0) + run1
+			a2 := a1 + run2
+			cur = append(cur, a1, a2)
+			a0 = a2
+		case "vertical":
+			a1 := b1 + delta
+			cur = append(cur, a1)
+			a0 = a1
+			c = 1 - c
+		}
+	}
+	return append(cur, width, width)
+}
+
+func readMode(t *testing.T, r *bitReader) (string, int) {
+	t.Helper()
+	bit := func() int {
+		b, err := r.readBit()
+		if err != nil {
+			t.Fatalf("readMode: %v", err)
+		}
+		return b
+	}
+
+	if bit() == 1 {
+		return "vertical", 0 // V0
+	}
+	if bit() == 1 {
+		if bit() == 1 {
+			return "vertical", 1 // VR1 "011"
+		}
+		return "vertical", -1 // VL1 "010"
+	}
+	if bit() == 1 {
+		return "horizontal", 0 // "001"
+	}
+	if bit() == 1 {
+		return "pass", 0 // "0001"
+	}
+	if bit() == 1 {
+		if bit() == 1 {
+			return "vertical", 2 // VR2 "000011"
+		}
+		return "vertical", -2 // VL2 "000010"
+	}
+	bit() // consume the fixed 1 bit shared by VR3 "0000011" and VL3 "0000010"
+	if bit() == 1 {
+		return "vertical", 3 // VR3 "0000011"
+	}
+	return "vertical", -3 // VL3 "0000010"
+}
@@ -0,0 +1,133 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+// Package imagesupport validates and produces the TIFF 6.0 images carried
+// in imagecashletter ImageViewData records, per the ANSI TS X9.100-181
+// profile Check 21 image exchange requires: bitonal, CCITT Group 4
+// compressed, 200 DPI.
+package imagesupport
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// TIFF tag IDs used by the X9.100-181 image profile. The standard allows
+// many more baseline TIFF tags; these are the ones Validate inspects and
+// packTIFF writes.
+const (
+	tagNewSubfileType            = 254
+	tagImageWidth                = 256
+	tagImageLength               = 257
+	tagBitsPerSample             = 258
+	tagCompression               = 259
+	tagPhotometricInterpretation = 262
+	tagStripOffsets              = 273
+	tagRowsPerStrip              = 278
+	tagStripByteCounts           = 279
+	tagXResolution               = 282
+	tagYResolution               = 283
+	tagResolutionUnit            = 296
+)
+
+// Compression values relevant to Check 21 images.
+const (
+	CompressionNone     = 1
+	CompressionPackBits = 5
+	CompressionG4       = 4 // CCITT Group 4 (T.6)
+)
+
+// ResolutionUnitInch is the TIFF ResolutionUnit value for dots-per-inch.
+const ResolutionUnitInch = 2
+
+// ifdEntry is one decoded Image File Directory entry.
+type ifdEntry struct {
+	tag      uint16
+	typ      uint16
+	count    uint32
+	value    uint32 // valid when the value fits inline; offset otherwise
+	rational [2]uint32
+}
+
+// tiffImage is the subset of a decoded TIFF file Validate and Transcode
+// need: the byte order, the first IFD's entries, and the raw file bytes
+// (so strip data can be located by offset).
+type tiffImage struct {
+	order   binary.ByteOrder
+	entries map[uint16]ifdEntry
+	raw     []byte
+}
+
+func parseTIFF(data []byte) (*tiffImage, error) {
+	if len(data) < 8 {
+		return nil, fmt.Errorf("imagesupport: image is too short to be a TIFF file (%d bytes)", len(data))
+	}
+	var order binary.ByteOrder
+	switch {
+	case data[0] == 'I' && data[1] == 'I':
+		order = binary.LittleEndian
+	case data[0] == 'M' && data[1] == 'M':
+		order = binary.BigEndian
+	default:
+		return nil, fmt.Errorf("imagesupport: missing TIFF byte-order marker")
+	}
+	if magic := order.Uint16(data[2:4]); magic != 42 {
+		return nil, fmt.Errorf("imagesupport: bad TIFF magic number %d", magic)
+	}
+	ifdOffset := order.Uint32(data[4:8])
+	if int(ifdOffset)+2 > len(data) {
+		return nil, fmt.Errorf("imagesupport: IFD offset %d out of range", ifdOffset)
+	}
+
+	count := order.Uint16(data[ifdOffset : ifdOffset+2])
+	entries := make(map[uint16]ifdEntry, count)
+	pos := int(ifdOffset) + 2
+	for i := 0; i < int(count); i++ {
+		if pos+12 > len(data) {
+			return nil, fmt.Errorf("imagesupport: truncated IFD entry %d", i)
+		}
+		e := ifdEntry{
+			tag:   order.Uint16(data[pos : pos+2]),
+			typ:   order.Uint16(data[pos+2 : pos+4]),
+			count: order.Uint32(data[pos+4 : pos+8]),
+		}
+		if e.typ == 5 { // RATIONAL: value field is an offset to two uint32s
+			off := order.Uint32(data[pos+8 : pos+12])
+			if int(off)+8 <= len(data) {
+				e.rational[0] = order.Uint32(data[off : off+4])
+				e.rational[1] = order.Uint32(data[off+4 : off+8])
+			}
+		} else if e.typ == 3 { // SHORT: an inline value occupies the first two bytes
+			e.value = uint32(order.Uint16(data[pos+8 : pos+10]))
+		} else {
+			e.value = order.Uint32(data[pos+8 : pos+12])
+		}
+		entries[e.tag] = e
+		pos += 12
+	}
+
+	return &tiffImage{order: order, entries: entries, raw: data}, nil
+}
+
+func (t *tiffImage) uint(tag uint16) (uint32, bool) {
+	e, ok := t.entries[tag]
+	if !ok {
+		return 0, false
+	}
+	return e.value, true
+}
+
+// rational returns a RATIONAL tag's value, numerator divided by
+// denominator, treating a zero denominator as 1.
+func (t *tiffImage) rational(tag uint16) (float64, bool) {
+	e, ok := t.entries[tag]
+	if !ok {
+		return 0, false
+	}
+	den := e.rational[1]
+	if den == 0 {
+		den = 1
+	}
+	return float64(e.rational[0]) / float64(den), true
+}
@@ -0,0 +1,149 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package imagesupport
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image"
+	_ "image/jpeg"
+	_ "image/png"
+)
+
+// bitonalThreshold is the gray level, out of 255, at or above which a pixel
+// is treated as white when thresholding a scanned image to 1-bit.
+const bitonalThreshold = 128
+
+// Transcode takes an already decoded image, such as a scanner's PNG or JPEG
+// output read with image.Decode (this package registers both formats), and
+// packs it into a bitonal, CCITT Group 4 (T.6) compressed TIFF 6.0 file at
+// 200 DPI, suitable for an ImageViewData.ImageData field. The result
+// passes Validate under DefaultProfile without any further recompression.
+func Transcode(r image.Image) ([]byte, error) {
+	bounds := r.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width <= 0 || height <= 0 {
+		return nil, fmt.Errorf("imagesupport: image has no pixels")
+	}
+
+	bitonal := thresholdToBitonal(r)
+	packed := packG4Encode(bitonal, width, height)
+
+	return packTIFF(width, height, CompressionG4, packed), nil
+}
+
+// thresholdToBitonal converts img to MSB-first packed 1-bit rows (1 =
+// white, 0 = black; WhiteIsZero photometric is handled by packTIFF).
+func thresholdToBitonal(img image.Image) []byte {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	stride := (width + 7) / 8
+	out := make([]byte, stride*height)
+
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			gray := grayLevel(img.At(bounds.Min.X+x, bounds.Min.Y+y))
+			if gray >= bitonalThreshold {
+				out[y*stride+x/8] |= 1 << uint(7-x%8)
+			}
+		}
+	}
+	return out
+}
+
+func grayLevel(c interface{ RGBA() (r, g, b, a uint32) }) int {
+	r, g, b, _ := c.RGBA()
+	// Rec. 601 luma, scaled from 16-bit to 8-bit channels.
+	y := (299*int(r>>8) + 587*int(g>>8) + 114*int(b>>8)) / 1000
+	return y
+}
+
+// packBitsEncode applies the TIFF PackBits (RLE) scheme to a packed bitonal
+// image as one byte stream; runs are not split at row boundaries.
+func packBitsEncode(rows []byte) []byte {
+	var out bytes.Buffer
+	for i := 0; i < len(rows); {
+		// literal/replicate run detection over the raw packed bytes;
+		// rows are short enough (<=row-width/8 bytes) that encoding
+		// whole-row boundaries separately isn't required for
+		// correctness, only for optimal compression.
+		runStart := i
+		for i < len(rows) && i-runStart < 127 && sameByteRun(rows, runStart, i) {
+			i++
+		}
+		if i-runStart >= 2 {
+			out.WriteByte(byte(257 - (i - runStart)))
+			out.WriteByte(rows[runStart])
+			continue
+		}
+		litStart := runStart
+		i = runStart + 1
+		for i < len(rows) && i-litStart < 128 && !sameByteRun(rows, i-1, i) {
+			i++
+		}
+		out.WriteByte(byte(i - litStart - 1))
+		out.Write(rows[litStart:i])
+	}
+	return out.Bytes()
+}
+
+func sameByteRun(b []byte, start, i int) bool {
+	return i < len(b) && b[i] == b[start]
+}
+
+// packTIFF writes a minimal, single-strip, little-endian TIFF 6.0 file
+// wrapping a bitonal image at 200 DPI, already compressed with the given
+// Compression tag value (CompressionG4 or CompressionPackBits).
+func packTIFF(width, height int, compression uint32, packed []byte) []byte {
+	const (
+		headerLen  = 8
+		numEntries = 12
+		ifdLen     = 2 + numEntries*12 + 4
+	)
+	rationalOffset := uint32(headerLen + ifdLen)
+	imageOffset := rationalOffset + 16 // two RATIONALs (X/YResolution)
+
+	var buf bytes.Buffer
+	order := binary.LittleEndian
+
+	buf.WriteString("II")
+	binary.Write(&buf, order, uint16(42))
+	binary.Write(&buf, order, uint32(headerLen))
+
+	binary.Write(&buf, order, uint16(numEntries))
+	writeEntry := func(tag, typ uint16, count, value uint32) {
+		binary.Write(&buf, order, tag)
+		binary.Write(&buf, order, typ)
+		binary.Write(&buf, order, count)
+		binary.Write(&buf, order, value)
+	}
+	const (
+		typeShort    = 3
+		typeLong     = 4
+		typeRational = 5
+	)
+	writeEntry(tagNewSubfileType, typeLong, 1, 0)
+	writeEntry(tagImageWidth, typeLong, 1, uint32(width))
+	writeEntry(tagImageLength, typeLong, 1, uint32(height))
+	writeEntry(tagBitsPerSample, typeShort, 1, 1)
+	writeEntry(tagCompression, typeShort, 1, compression)
+	writeEntry(tagPhotometricInterpretation, typeShort, 1, 0) // WhiteIsZero
+	writeEntry(tagStripOffsets, typeLong, 1, imageOffset)
+	writeEntry(tagRowsPerStrip, typeLong, 1, uint32(height))
+	writeEntry(tagStripByteCounts, typeLong, 1, uint32(len(packed)))
+	writeEntry(tagXResolution, typeRational, 1, rationalOffset)
+	writeEntry(tagYResolution, typeRational, 1, rationalOffset+8)
+	writeEntry(tagResolutionUnit, typeShort, 1, ResolutionUnitInch)
+	binary.Write(&buf, order, uint32(0)) // no next IFD
+
+	binary.Write(&buf, order, uint32(200))
+	binary.Write(&buf, order, uint32(1))
+	binary.Write(&buf, order, uint32(200))
+	binary.Write(&buf, order, uint32(1))
+
+	buf.Write(packed)
+	return buf.Bytes()
+}
@@ -0,0 +1,87 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package imagesupport
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+// packBitsDecode is the inverse of packBitsEncode, used only to verify the
+// encoder round-trips correctly.
+func packBitsDecode(t *testing.T, data []byte) []byte {
+	t.Helper()
+	var out []byte
+	for i := 0; i < len(data); {
+		n := int(int8(data[i]))
+		i++
+		switch {
+		case n >= 0:
+			count := n + 1
+			if i+count > len(data) {
+				t.Fatalf("literal run overruns buffer at %d", i)
+			}
+			out = append(out, data[i:i+count]...)
+			i += count
+		case n != -128:
+			count := 1 - n
+			if i >= len(data) {
+				t.Fatalf("replicate run overruns buffer at %d", i)
+			}
+			for j := 0; j < count; j++ {
+				out = append(out, data[i])
+			}
+			i++
+		}
+	}
+	return out
+}
+
+func TestPackBitsEncodeRoundTrip(t *testing.T) {
+	cases := [][]byte{
+		{0xAA, 0xBB, 0xCC},
+		{0xAA, 0xAA, 0xAA, 0xBB},
+		{0x00, 0xFF, 0x00, 0xFF, 0x00},
+		{0x11, 0x11, 0x11, 0x11, 0x11, 0x22, 0x33, 0x33},
+	}
+	for _, rows := range cases {
+		encoded := packBitsEncode(rows)
+		got := packBitsDecode(t, encoded)
+		if string(got) != string(rows) {
+			t.Fatalf("packBitsEncode/decode round trip: got %x, want %x", got, rows)
+		}
+	}
+}
+
+func TestTranscodeProducesValidatableBitonalTIFF(t *testing.T) {
+	img := image.NewGray(image.Rect(0, 0, 16, 8))
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 16; x++ {
+			if (x+y)%2 == 0 {
+				img.Set(x, y, color.White)
+			} else {
+				img.Set(x, y, color.Black)
+			}
+		}
+	}
+
+	data, err := Transcode(img)
+	if err != nil {
+		t.Fatalf("Transcode: %v", err)
+	}
+
+	// Transcode's output is CCITT Group 4 compressed, so it satisfies
+	// the strict default profile without any further recompression.
+	if err := Validate(data, FrontSide, DefaultProfile); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+}
+
+func TestValidateRejectsTruncatedImage(t *testing.T) {
+	if err := Validate([]byte("not a tiff"), FrontSide, DefaultProfile); err == nil {
+		t.Fatalf("expected error validating non-TIFF data")
+	}
+}
@@ -0,0 +1,124 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package imagesupport
+
+import "fmt"
+
+// ViewSide mirrors ImageViewDetail.ViewSide: which side of the physical
+// item an ImageViewData's image was captured from.
+type ViewSide int
+
+const (
+	FrontSide ViewSide = 1
+	BackSide  ViewSide = 2
+)
+
+// ValidationError describes why an image failed the X9.100-181 profile.
+type ValidationError struct {
+	Tag string
+	Msg string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Tag, e.Msg)
+}
+
+// Profile is the set of constraints ANSI TS X9.100-181 places on an image
+// submitted for Check 21 exchange.
+type Profile struct {
+	// RequireGroup4 rejects any image whose Compression tag isn't 4
+	// (CCITT Group 4 / T.6). DefaultProfile sets it; withDefaults does
+	// not, so a Profile that leaves it false accepts PackBits and
+	// uncompressed bitonal images.
+	RequireGroup4 bool
+	// DPI is the required resolution in both axes. Defaults to 200,
+	// the DPI Check 21 image exchange specifies.
+	DPI uint32
+}
+
+// DefaultProfile is the strict ANSI TS X9.100-181 profile: bitonal, 200
+// DPI, CCITT Group 4 compressed.
+var DefaultProfile = Profile{RequireGroup4: true, DPI: 200}
+
+func (p Profile) withDefaults() Profile {
+	if p.DPI == 0 {
+		p.DPI = 200
+	}
+	return p
+}
+
+// Validate checks image (the raw bytes of an ImageViewData.ImageData
+// field) against profile. side identifies which ImageViewDetail the image
+// belongs to and is only used to annotate errors.
+func Validate(image []byte, side ViewSide, profile Profile) error {
+	profile = profile.withDefaults()
+
+	t, err := parseTIFF(image)
+	if err != nil {
+		return err
+	}
+
+	if bits, ok := t.uint(tagBitsPerSample); !ok || bits != 1 {
+		return &ValidationError{Tag: "BitsPerSample", Msg: fmt.Sprintf("must be 1 (bitonal) for side %d, got %d", side, bits)}
+	}
+
+	compression, _ := t.uint(tagCompression)
+	if profile.RequireGroup4 && compression != CompressionG4 {
+		return &ValidationError{Tag: "Compression", Msg: fmt.Sprintf("must be CCITT Group 4 (4) for side %d, got %d", side, compression)}
+	}
+
+	photometric, ok := t.uint(tagPhotometricInterpretation)
+	if !ok || (photometric != 0 && photometric != 1) {
+		return &ValidationError{Tag: "PhotometricInterpretation", Msg: fmt.Sprintf("must be 0 (WhiteIsZero) or 1 (BlackIsZero) for side %d, got %d", side, photometric)}
+	}
+
+	unit, _ := t.uint(tagResolutionUnit)
+	if unit != ResolutionUnitInch {
+		return &ValidationError{Tag: "ResolutionUnit", Msg: fmt.Sprintf("must be inches (2) for side %d, got %d", side, unit)}
+	}
+	for _, tag := range []struct {
+		id   uint16
+		name string
+	}{{tagXResolution, "XResolution"}, {tagYResolution, "YResolution"}} {
+		dpi, ok := t.rational(tag.id)
+		if !ok || uint32(dpi+0.5) != profile.DPI {
+			return &ValidationError{Tag: tag.name, Msg: fmt.Sprintf("must be %d DPI for side %d, got %.1f", profile.DPI, side, dpi)}
+		}
+	}
+
+	rowsPerStrip, _ := t.uint(tagRowsPerStrip)
+	length, _ := t.uint(tagImageLength)
+	stripOffsets := t.entries[tagStripOffsets]
+	stripByteCounts := t.entries[tagStripByteCounts]
+	if rowsPerStrip == 0 || length == 0 {
+		return &ValidationError{Tag: "RowsPerStrip", Msg: fmt.Sprintf("image dimensions missing for side %d", side)}
+	}
+	expectedStrips := (length + rowsPerStrip - 1) / rowsPerStrip
+	if stripOffsets.count != expectedStrips || stripByteCounts.count != expectedStrips {
+		return &ValidationError{Tag: "StripOffsets", Msg: fmt.Sprintf("expected %d strips for side %d, found %d offsets / %d byte counts", expectedStrips, side, stripOffsets.count, stripByteCounts.count)}
+	}
+
+	return nil
+}
+
+// ValidateFrontBackConsistency checks that a check or return's front and
+// back images agree on dimensions, as a scanner that rasterized both sides
+// in one pass should produce.
+func ValidateFrontBackConsistency(front, back []byte) error {
+	tf, err := parseTIFF(front)
+	if err != nil {
+		return fmt.Errorf("imagesupport: front image: %v", err)
+	}
+	tb, err := parseTIFF(back)
+	if err != nil {
+		return fmt.Errorf("imagesupport: back image: %v", err)
+	}
+	fw, _ := tf.uint(tagImageWidth)
+	bw, _ := tb.uint(tagImageWidth)
+	if fw != bw {
+		return &ValidationError{Tag: "ImageWidth", Msg: fmt.Sprintf("front/back width mismatch: %d vs %d", fw, bw)}
+	}
+	return nil
+}
@@ -0,0 +1,356 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package imagecashletter
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+)
+
+// ReaderOptions configures how a Reader frames and decodes records.
+type ReaderOptions struct {
+	// Format names the X9.37 variant, Discover or DSTU. The Reader does
+	// not read it: framing is chosen by Encoding alone, and every record
+	// is parsed with the same layout whatever Format says.
+	Format Format
+	// Encoding selects the byte-level framing: ASCIIEncoding,
+	// EBCDICEncoding, or VariableLengthEncoding.
+	Encoding Encoding
+	// MaxRecordLength bounds the length a VariableLengthEncoding (DSTU)
+	// record's 4 byte length prefix is allowed to declare, so a
+	// corrupt or malicious prefix can't force a multi-gigabyte
+	// allocation before any data has actually been read. Defaults to
+	// defaultMaxRecordLength, which comfortably fits the largest
+	// ImageViewData TIFF blobs this format carries.
+	MaxRecordLength uint32
+}
+
+// defaultMaxRecordLength is the ceiling applied to DSTU length prefixes
+// when ReaderOptions.MaxRecordLength is unset.
+const defaultMaxRecordLength = 64 << 20 // 64 MiB
+
+// Record is a single logical record read from, or to be written to, an
+// imagecashletter stream. Value holds a pointer to the concrete record
+// type (e.g. *FileHeader, *CheckDetail) selected by Type.
+type Record struct {
+	Type  string
+	Value fmt.Stringer
+}
+
+// Reader reads an X9.37 file one record at a time so large files, with
+// their embedded TIFF image blobs, never need to be held in memory in
+// full. Use NewFileFromReader for the common case of assembling a *File.
+type Reader struct {
+	framer recordFramer
+	buf    *bufio.Reader
+	opts   ReaderOptions
+	line   int
+}
+
+// NewReader returns a Reader that frames records from r according to opts.
+func NewReader(r io.Reader, opts ReaderOptions) (*Reader, error) {
+	if opts.MaxRecordLength == 0 {
+		opts.MaxRecordLength = defaultMaxRecordLength
+	}
+	framer, err := newRecordFramer(opts.Encoding, opts.MaxRecordLength)
+	if err != nil {
+		return nil, err
+	}
+	return &Reader{
+		framer: framer,
+		buf:    bufio.NewReader(r),
+		opts:   opts,
+	}, nil
+}
+
+// Next returns the next Record in the stream, or io.EOF once the stream is
+// exhausted.
+func (r *Reader) Next() (Record, error) {
+	raw, err := r.framer.next(r.buf)
+	if err != nil {
+		return Record{}, err
+	}
+	r.line++
+	if len(raw) < 2 {
+		return Record{}, &FileError{FieldName: "RecordType", Value: string(raw), Msg: fmt.Sprintf(msgUnknownRecordType, string(raw))}
+	}
+	recordType := string(raw[:2])
+	value, err := newRecord(recordType)
+	if err != nil {
+		return Record{}, err
+	}
+	if err := value.Parse(string(raw)); err != nil {
+		return Record{}, fmt.Errorf("line %d: %v", r.line, err)
+	}
+	return Record{Type: recordType, Value: value}, nil
+}
+
+// Each calls fn with every Record in the stream until EOF or fn/Next
+// returns an error.
+func (r *Reader) Each(fn func(Record) error) error {
+	for {
+		rec, err := r.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := fn(rec); err != nil {
+			return err
+		}
+	}
+}
+
+// parsedRecord is implemented by every imagecashletter record type.
+type parsedRecord interface {
+	fmt.Stringer
+	Parse(record string) error
+}
+
+// newRecord returns a zero-value record for the given record type code.
+func newRecord(recordType string) (parsedRecord, error) {
+	switch recordType {
+	case fileHeaderPos:
+		v := NewFileHeader()
+		return &v, nil
+	case cashLetterHeaderPos:
+		v := NewCashLetterHeader()
+		return &v, nil
+	case bundleHeaderPos:
+		v := NewBundleHeader()
+		return &v, nil
+	case checkDetailPos:
+		v := NewCheckDetail()
+		return &v, nil
+	case checkDetailAddendumAPos:
+		v := NewCheckDetailAddendumA()
+		return &v, nil
+	case checkDetailAddendumBPos:
+		v := NewCheckDetailAddendumB()
+		return &v, nil
+	case checkDetailAddendumCPos:
+		v := NewCheckDetailAddendumC()
+		return &v, nil
+	case returnDetailPos:
+		v := NewReturnDetail()
+		return &v, nil
+	case returnAddendumAPos:
+		v := NewReturnDetailAddendumA()
+		return &v, nil
+	case returnAddendumBPos:
+		v := NewReturnDetailAddendumB()
+		return &v, nil
+	case returnAddendumCPos:
+		v := NewReturnDetailAddendumC()
+		return &v, nil
+	case returnAddendumDPos:
+		v := NewReturnDetailAddendumD()
+		return &v, nil
+	case imageViewDetailPos:
+		v := NewImageViewDetail()
+		return &v, nil
+	case imageViewDataPos:
+		v := NewImageViewData()
+		return &v, nil
+	case imageViewAnalysisPos:
+		v := NewImageViewAnalysis()
+		return &v, nil
+	case creditItemPos:
+		v := NewCreditItem()
+		return &v, nil
+	case bundleControlPos:
+		v := NewBundleControl()
+		return &v, nil
+	case routingNumberSummaryPos:
+		v := NewRoutingNumberSummary()
+		return &v, nil
+	case cashLetterControlPos:
+		v := NewCashLetterControl()
+		return &v, nil
+	case fileControlPos:
+		v := NewFileControl()
+		return &v, nil
+	case userDefinedSignaturePos:
+		return &Signature{}, nil
+	default:
+		return nil, &FileError{FieldName: "RecordType", Value: recordType, Msg: fmt.Sprintf(msgUnknownRecordType, recordType)}
+	}
+}
+
+// NewFileFromReader reads r in full, framing and parsing records according
+// to opts, and assembles a *File. It is the streaming equivalent of
+// FileFromJSON for raw X9.37/DSTU input.
+func NewFileFromReader(r io.Reader, opts ReaderOptions) (*File, error) {
+	reader, err := NewReader(r, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	file := NewFile()
+	asm := &assembler{file: file}
+	if err := reader.Each(asm.add); err != nil {
+		return nil, err
+	}
+	if err := asm.finish(); err != nil {
+		return nil, err
+	}
+	return file, nil
+}
+
+// assembler folds a flat stream of Records back into the nested
+// File/CashLetter/Bundle/CheckDetail|ReturnDetail structure that Create and
+// Validate operate on.
+type assembler struct {
+	file *File
+
+	cashLetter *CashLetter
+	bundle     *Bundle
+	check      *CheckDetail
+	ret        *ReturnDetail
+}
+
+func (a *assembler) add(rec Record) error {
+	switch v := rec.Value.(type) {
+	case *FileHeader:
+		a.file.Header = *v
+	case *CashLetterHeader:
+		a.closeBundle()
+		a.closeCashLetter()
+		a.cashLetter = &CashLetter{CashLetterHeader: *v}
+	case *CashLetterControl:
+		a.closeBundle()
+		if a.cashLetter != nil {
+			a.cashLetter.CashLetterControl = *v
+		}
+	case *BundleHeader:
+		a.closeCheckOrReturn()
+		a.closeBundle()
+		a.bundle = &Bundle{BundleHeader: *v}
+	case *BundleControl:
+		a.closeCheckOrReturn()
+		if a.bundle != nil {
+			a.bundle.BundleControl = *v
+		}
+	case *CheckDetail:
+		a.closeCheckOrReturn()
+		a.check = v
+	case *CheckDetailAddendumA:
+		if a.check != nil {
+			a.check.CheckDetailAddendumA = append(a.check.CheckDetailAddendumA, *v)
+		}
+	case *CheckDetailAddendumB:
+		if a.check != nil {
+			a.check.CheckDetailAddendumB = append(a.check.CheckDetailAddendumB, *v)
+		}
+	case *CheckDetailAddendumC:
+		if a.check != nil {
+			a.check.CheckDetailAddendumC = append(a.check.CheckDetailAddendumC, *v)
+		}
+	case *ReturnDetail:
+		a.closeCheckOrReturn()
+		a.ret = v
+	case *ReturnDetailAddendumA:
+		if a.ret != nil {
+			a.ret.ReturnDetailAddendumA = append(a.ret.ReturnDetailAddendumA, *v)
+		}
+	case *ReturnDetailAddendumB:
+		if a.ret != nil {
+			a.ret.ReturnDetailAddendumB = append(a.ret.ReturnDetailAddendumB, *v)
+		}
+	case *ReturnDetailAddendumC:
+		if a.ret != nil {
+			a.ret.ReturnDetailAddendumC = append(a.ret.ReturnDetailAddendumC, *v)
+		}
+	case *ReturnDetailAddendumD:
+		if a.ret != nil {
+			a.ret.ReturnDetailAddendumD = append(a.ret.ReturnDetailAddendumD, *v)
+		}
+	case *ImageViewDetail:
+		a.addImageViewDetail(*v)
+	case *ImageViewData:
+		a.addImageViewData(*v)
+	case *ImageViewAnalysis:
+		a.addImageViewAnalysis(*v)
+	case *CreditItem:
+		if a.cashLetter != nil {
+			a.cashLetter.CreditItems = append(a.cashLetter.CreditItems, *v)
+		}
+	case *RoutingNumberSummary:
+		if a.cashLetter != nil {
+			a.cashLetter.RoutingNumberSummary = append(a.cashLetter.RoutingNumberSummary, *v)
+		}
+	case *Signature:
+		a.file.Signature = v
+	case *FileControl:
+		a.closeCheckOrReturn()
+		a.closeBundle()
+		a.closeCashLetter()
+		a.file.Control = *v
+	default:
+		return &FileError{FieldName: "RecordType", Value: rec.Type, Msg: fmt.Sprintf(msgUnknownRecordType, rec.Type)}
+	}
+	return nil
+}
+
+func (a *assembler) addImageViewDetail(v ImageViewDetail) {
+	switch {
+	case a.check != nil:
+		a.check.ImageViewDetail = append(a.check.ImageViewDetail, v)
+	case a.ret != nil:
+		a.ret.ImageViewDetail = append(a.ret.ImageViewDetail, v)
+	}
+}
+
+func (a *assembler) addImageViewData(v ImageViewData) {
+	switch {
+	case a.check != nil:
+		a.check.ImageViewData = append(a.check.ImageViewData, v)
+	case a.ret != nil:
+		a.ret.ImageViewData = append(a.ret.ImageViewData, v)
+	}
+}
+
+func (a *assembler) addImageViewAnalysis(v ImageViewAnalysis) {
+	switch {
+	case a.check != nil:
+		a.check.ImageViewAnalysis = append(a.check.ImageViewAnalysis, v)
+	case a.ret != nil:
+		a.ret.ImageViewAnalysis = append(a.ret.ImageViewAnalysis, v)
+	}
+}
+
+func (a *assembler) closeCheckOrReturn() {
+	if a.check != nil && a.bundle != nil {
+		a.bundle.Checks = append(a.bundle.Checks, *a.check)
+	}
+	if a.ret != nil && a.bundle != nil {
+		a.bundle.Returns = append(a.bundle.Returns, *a.ret)
+	}
+	a.check = nil
+	a.ret = nil
+}
+
+func (a *assembler) closeBundle() {
+	if a.bundle != nil && a.cashLetter != nil {
+		a.cashLetter.Bundles = append(a.cashLetter.Bundles, *a.bundle)
+	}
+	a.bundle = nil
+}
+
+func (a *assembler) closeCashLetter() {
+	if a.cashLetter != nil {
+		a.file.AddCashLetter(*a.cashLetter)
+	}
+	a.cashLetter = nil
+}
+
+func (a *assembler) finish() error {
+	a.closeCheckOrReturn()
+	a.closeBundle()
+	a.closeCashLetter()
+	return nil
+}
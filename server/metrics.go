@@ -0,0 +1,28 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package server
+
+import "github.com/prometheus/client_golang/prometheus"
+
+var (
+	parseLatency = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name: "imagecashletter_parse_latency_seconds",
+		Help: "Latency, in seconds, to parse an uploaded file.",
+	}, []string{"format"})
+
+	fileSizeBytes = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:    "imagecashletter_file_size_bytes",
+		Help:    "Size, in bytes, of files uploaded to the service.",
+		Buckets: prometheus.ExponentialBuckets(1024, 4, 10), // 1KB .. ~256MB
+	})
+)
+
+func init() {
+	prometheus.MustRegister(parseLatency, fileSizeBytes)
+}
+
+// validation error counts by FieldName are tracked by imagecashletter's
+// own ValidateAll, which this package's validateFile handler calls; see
+// imagecashletter_validation_errors.
@@ -0,0 +1,253 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+// Package server exposes imagecashletter's parse, validate, convert, and
+// merge operations as an HTTP service, so a deployment can run this
+// library as a standalone microservice rather than a Go dependency.
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/gorilla/mux"
+	"github.com/moov-io/base"
+	"github.com/moov-io/imagecashletter"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Server handles HTTP requests for parsing, validating, converting, and
+// merging imagecashletter Files.
+type Server struct {
+	logger log.Logger
+	store  *fileStore
+	router *mux.Router
+}
+
+// New returns a Server with its routes registered. Callers typically pass
+// the result of Handler() to http.ListenAndServe (or a test server).
+func New(logger log.Logger) *Server {
+	if logger == nil {
+		logger = log.NewNopLogger()
+	}
+	s := &Server{
+		logger: logger,
+		store:  newFileStore(),
+		router: mux.NewRouter(),
+	}
+	s.router.HandleFunc("/files", s.createFile).Methods("POST")
+	s.router.HandleFunc("/files/merge", s.mergeFiles).Methods("POST")
+	s.router.HandleFunc("/files/{id}", s.deleteFile).Methods("DELETE")
+	s.router.HandleFunc("/files/{id}/validate", s.validateFile).Methods("POST")
+	s.router.HandleFunc("/files/{id}/contents", s.fileContents).Methods("GET")
+	s.router.Handle("/metrics", promhttp.Handler()).Methods("GET")
+	return s
+}
+
+// Handler returns the Server's http.Handler.
+func (s *Server) Handler() http.Handler {
+	return s.router
+}
+
+// createdFile is the response body for a successful POST /files.
+type createdFile struct {
+	ID string `json:"id"`
+}
+
+// createFile accepts either JSON (Content-Type: application/json) or raw
+// X9.37/DSTU (Content-Type: application/octet-stream) file bytes, parses
+// them, assigns an ID, and stores the result. JSON input is also validated
+// by FileFromJSON; raw X9.37/DSTU input is only parsed.
+func (s *Server) createFile(w http.ResponseWriter, r *http.Request) {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	fileSizeBytes.Observe(float64(len(body)))
+
+	format := "x937"
+	if r.URL.Query().Get("format") == "dstu" {
+		format = "dstu"
+	}
+
+	start := time.Now()
+	var file *imagecashletter.File
+	if r.Header.Get("Content-Type") == "application/json" {
+		file, err = imagecashletter.FileFromJSON(body)
+		format = "json"
+	} else {
+		enc := imagecashletter.ASCIIEncoding
+		icFormat := imagecashletter.Discover
+		if format == "dstu" {
+			enc = imagecashletter.VariableLengthEncoding
+			icFormat = imagecashletter.DSTU
+		}
+		file, err = imagecashletter.NewFileFromReader(bytes.NewReader(body), imagecashletter.ReaderOptions{
+			Format:   icFormat,
+			Encoding: enc,
+		})
+	}
+	parseLatency.WithLabelValues(format).Observe(time.Since(start).Seconds())
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	file.ID = base.ID()
+	s.store.put(file)
+
+	writeJSON(w, http.StatusCreated, createdFile{ID: file.ID})
+}
+
+// validationErrorResponse is one entry of a validateFile response body,
+// describing a single failure located within the file's nested structure.
+type validationErrorResponse struct {
+	Path      string `json:"path"`
+	FieldName string `json:"fieldName"`
+	Value     string `json:"value,omitempty"`
+	Msg       string `json:"msg"`
+}
+
+// validateFile runs ValidateAll on a previously uploaded file and returns
+// every failure found, not just the first, so operators can see
+// everything wrong with a submitted file in one response. ValidateAll
+// also records each failing FieldName against imagecashletter's own
+// imagecashletter_validation_errors counter.
+func (s *Server) validateFile(w http.ResponseWriter, r *http.Request) {
+	file, ok := s.store.get(mux.Vars(r)["id"])
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	report := file.ValidateAll()
+	if len(report.Errors) == 0 {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	errs := make([]validationErrorResponse, len(report.Errors))
+	for i, pe := range report.Errors {
+		errs[i] = validationErrorResponse{
+			Path:      pe.Path,
+			FieldName: pe.Err.FieldName,
+			Value:     pe.Err.Value,
+			Msg:       pe.Err.Msg,
+		}
+	}
+	writeJSON(w, http.StatusBadRequest, struct {
+		Errors []validationErrorResponse `json:"errors"`
+	}{Errors: errs})
+}
+
+// fileContents returns a previously uploaded file re-encoded as JSON,
+// fixed-width ASCII X9.37, or DSTU, per the format query parameter
+// (default json).
+func (s *Server) fileContents(w http.ResponseWriter, r *http.Request) {
+	file, ok := s.store.get(mux.Vars(r)["id"])
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	switch r.URL.Query().Get("format") {
+	case "x937":
+		var buf bytes.Buffer
+		if err := file.Write(&buf, imagecashletter.WriterOptions{Format: imagecashletter.Discover, Encoding: imagecashletter.ASCIIEncoding}); err != nil {
+			s.writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Write(buf.Bytes())
+	case "dstu":
+		var buf bytes.Buffer
+		if err := file.Write(&buf, imagecashletter.WriterOptions{Format: imagecashletter.DSTU, Encoding: imagecashletter.VariableLengthEncoding}); err != nil {
+			s.writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Write(buf.Bytes())
+	default:
+		writeJSON(w, http.StatusOK, file)
+	}
+}
+
+// mergeFiles concatenates the CashLetters of every uploaded file body
+// (multipart form field "file", repeated) into one new File and
+// recomputes its FileControl.
+func (s *Server) mergeFiles(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseMultipartForm(32 << 20); err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	parts := r.MultipartForm.File["file"]
+	if len(parts) == 0 {
+		s.writeError(w, http.StatusBadRequest, fmt.Errorf("no file parts provided"))
+		return
+	}
+
+	merged := imagecashletter.NewFile()
+	for _, fh := range parts {
+		f, err := fh.Open()
+		if err != nil {
+			s.writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		body, err := ioutil.ReadAll(f)
+		f.Close()
+		if err != nil {
+			s.writeError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		file, err := imagecashletter.FileFromJSON(body)
+		if err != nil {
+			s.writeError(w, http.StatusBadRequest, fmt.Errorf("%s: %v", fh.Filename, err))
+			return
+		}
+		if merged.Header.ImmediateOrigin == "" {
+			merged.Header = file.Header
+		}
+		merged.CashLetters = append(merged.CashLetters, file.CashLetters...)
+	}
+
+	if err := merged.Create(); err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	merged.ID = base.ID()
+	s.store.put(merged)
+
+	writeJSON(w, http.StatusCreated, createdFile{ID: merged.ID})
+}
+
+func (s *Server) deleteFile(w http.ResponseWriter, r *http.Request) {
+	if !s.store.delete(mux.Vars(r)["id"]) {
+		http.NotFound(w, r)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// writeError logs err (keyed by the HTTP status it produced) and writes it
+// to the client as a JSON error response.
+func (s *Server) writeError(w http.ResponseWriter, status int, err error) {
+	s.logger.Log("status", status, "err", err)
+	writeJSON(w, status, errorResponse{Error: err.Error()})
+}
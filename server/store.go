@@ -0,0 +1,47 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package server
+
+import (
+	"sync"
+
+	"github.com/moov-io/imagecashletter"
+)
+
+// fileStore is a process-local, in-memory registry of uploaded files keyed
+// by ID. A production deployment would back this with durable storage;
+// this is enough to support the parse/validate/contents/merge/delete
+// lifecycle a single server instance needs to offer.
+type fileStore struct {
+	mu    sync.RWMutex
+	files map[string]*imagecashletter.File
+}
+
+func newFileStore() *fileStore {
+	return &fileStore{files: make(map[string]*imagecashletter.File)}
+}
+
+func (s *fileStore) put(f *imagecashletter.File) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.files[f.ID] = f
+}
+
+func (s *fileStore) get(id string) (*imagecashletter.File, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	f, ok := s.files[id]
+	return f, ok
+}
+
+func (s *fileStore) delete(id string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.files[id]; !ok {
+		return false
+	}
+	delete(s.files, id)
+	return true
+}
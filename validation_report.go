@@ -0,0 +1,149 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package imagecashletter
+
+import (
+	"fmt"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var validationErrorsByField = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "imagecashletter_validation_errors",
+	Help: "Count of validation errors accumulated by ValidateAll, keyed by the FieldName that failed.",
+}, []string{"field"})
+
+func init() {
+	prometheus.MustRegister(validationErrorsByField)
+}
+
+// PathError pairs a validation failure with the location in the File's
+// nested structure it came from, e.g.
+// "cashLetters[3].bundles[1].checks[17].CheckDetailAddendumB[0]".
+type PathError struct {
+	Path string
+	Err  *FileError
+}
+
+func (e *PathError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Path, e.Err)
+}
+
+func (e *PathError) Unwrap() error {
+	return e.Err
+}
+
+// ValidationReport collects every validation failure found by
+// File.ValidateAll, rather than stopping at the first one.
+type ValidationReport struct {
+	Errors []*PathError
+}
+
+// FirstError returns the first error in the report, or nil if it is empty.
+// File.Validate is a thin wrapper around this for backward compatibility
+// with callers that only care whether a file is valid, not everything
+// wrong with it.
+func (r *ValidationReport) FirstError() error {
+	if r == nil || len(r.Errors) == 0 {
+		return nil
+	}
+	return r.Errors[0]
+}
+
+func (r *ValidationReport) add(path string, err error) {
+	if err == nil {
+		return
+	}
+	fe, ok := err.(*FileError)
+	if !ok {
+		fe = &FileError{FieldName: path, Msg: err.Error()}
+	}
+	validationErrorsByField.WithLabelValues(fe.FieldName).Inc()
+	r.Errors = append(r.Errors, &PathError{Path: path, Err: fe})
+}
+
+// ValidateAll walks f's FileHeader, every CashLetter/Bundle/CheckDetail/
+// ReturnDetail/addenda/image view/CreditItem, and every control record,
+// accumulating every validation failure instead of returning on the
+// first one. Use this to show a user everything wrong with a submitted
+// file in a single pass; use Validate for the common case of just
+// checking whether the file is valid.
+func (f *File) ValidateAll() *ValidationReport {
+	report := &ValidationReport{}
+	if f == nil {
+		report.add("file", ErrNilFile)
+		return report
+	}
+
+	report.add("fileHeader", f.Header.Validate())
+	report.add("file", f.CashLetterIDUnique())
+
+	for i, cl := range f.CashLetters {
+		clPath := fmt.Sprintf("cashLetters[%d]", i)
+		report.add(clPath+".CashLetterHeader", cl.CashLetterHeader.Validate())
+		report.add(clPath+".CashLetterControl", cl.CashLetterControl.Validate())
+
+		for j, ci := range cl.CreditItems {
+			report.add(fmt.Sprintf("%s.CreditItems[%d]", clPath, j), ci.Validate())
+		}
+		for j, rns := range cl.RoutingNumberSummary {
+			report.add(fmt.Sprintf("%s.RoutingNumberSummary[%d]", clPath, j), rns.Validate())
+		}
+
+		for j, b := range cl.Bundles {
+			bPath := fmt.Sprintf("%s.bundles[%d]", clPath, j)
+			report.add(bPath+".BundleHeader", b.BundleHeader.Validate())
+			report.add(bPath+".BundleControl", b.BundleControl.Validate())
+
+			for k, cd := range b.Checks {
+				cdPath := fmt.Sprintf("%s.checks[%d]", bPath, k)
+				report.add(cdPath, cd.Validate())
+				for l, a := range cd.CheckDetailAddendumA {
+					report.add(fmt.Sprintf("%s.CheckDetailAddendumA[%d]", cdPath, l), a.Validate())
+				}
+				for l, b := range cd.CheckDetailAddendumB {
+					report.add(fmt.Sprintf("%s.CheckDetailAddendumB[%d]", cdPath, l), b.Validate())
+				}
+				for l, c := range cd.CheckDetailAddendumC {
+					report.add(fmt.Sprintf("%s.CheckDetailAddendumC[%d]", cdPath, l), c.Validate())
+				}
+				reportImageViews(report, cdPath, cd.ImageViewDetail, cd.ImageViewData, cd.ImageViewAnalysis)
+			}
+
+			for k, rd := range b.Returns {
+				rdPath := fmt.Sprintf("%s.returns[%d]", bPath, k)
+				report.add(rdPath, rd.Validate())
+				for l, a := range rd.ReturnDetailAddendumA {
+					report.add(fmt.Sprintf("%s.ReturnDetailAddendumA[%d]", rdPath, l), a.Validate())
+				}
+				for l, b := range rd.ReturnDetailAddendumB {
+					report.add(fmt.Sprintf("%s.ReturnDetailAddendumB[%d]", rdPath, l), b.Validate())
+				}
+				for l, c := range rd.ReturnDetailAddendumC {
+					report.add(fmt.Sprintf("%s.ReturnDetailAddendumC[%d]", rdPath, l), c.Validate())
+				}
+				for l, d := range rd.ReturnDetailAddendumD {
+					report.add(fmt.Sprintf("%s.ReturnDetailAddendumD[%d]", rdPath, l), d.Validate())
+				}
+				reportImageViews(report, rdPath, rd.ImageViewDetail, rd.ImageViewData, rd.ImageViewAnalysis)
+			}
+		}
+	}
+
+	report.add("fileControl", f.Control.Validate())
+	return report
+}
+
+func reportImageViews(report *ValidationReport, path string, details []ImageViewDetail, datas []ImageViewData, analyses []ImageViewAnalysis) {
+	for i, d := range details {
+		report.add(fmt.Sprintf("%s.ImageViewDetail[%d]", path, i), d.Validate())
+	}
+	for i, d := range datas {
+		report.add(fmt.Sprintf("%s.ImageViewData[%d]", path, i), d.Validate())
+	}
+	for i, a := range analyses {
+		report.add(fmt.Sprintf("%s.ImageViewAnalysis[%d]", path, i), a.Validate())
+	}
+}
@@ -0,0 +1,196 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package imagecashletter
+
+import (
+	"io"
+)
+
+// WriterOptions configures how a Writer frames and encodes records.
+type WriterOptions struct {
+	// Format names the X9.37 variant, Discover or DSTU. The Writer does
+	// not read it: framing is chosen by Encoding alone, and every record
+	// is written with the same layout whatever Format says.
+	Format Format
+	// Encoding selects the byte-level framing: ASCIIEncoding,
+	// EBCDICEncoding, or VariableLengthEncoding.
+	Encoding Encoding
+}
+
+// Writer writes an X9.37 file one record at a time so large files, with
+// their embedded TIFF image blobs, never need to be held in memory in
+// full. Use WriteFile for the common case of serializing an entire *File.
+type Writer struct {
+	framer recordFramer
+	w      io.Writer
+	opts   WriterOptions
+}
+
+// NewWriter returns a Writer that frames records into w according to opts.
+func NewWriter(w io.Writer, opts WriterOptions) (*Writer, error) {
+	// maxRecordLength only bounds reads of an untrusted length prefix;
+	// it's irrelevant when writing, so the variableLengthFramer here
+	// never checks it.
+	framer, err := newRecordFramer(opts.Encoding, 0)
+	if err != nil {
+		return nil, err
+	}
+	return &Writer{framer: framer, w: w, opts: opts}, nil
+}
+
+// WriteRecord frames and writes a single Record.
+func (wr *Writer) WriteRecord(rec Record) error {
+	return wr.framer.write(wr.w, []byte(rec.Value.String()))
+}
+
+// WriteFile walks f's CashLetters/Bundles/Checks/Returns in file order and
+// streams every record out through the Writer's framer, followed by
+// f.Signature when it is set. Callers should call
+// File.Create beforehand so FileControl and the other control records are
+// up to date.
+func (wr *Writer) WriteFile(f *File) error {
+	if f == nil {
+		return ErrNilFile
+	}
+	write := func(v fmtStringer) error {
+		return wr.framer.write(wr.w, []byte(v.String()))
+	}
+
+	if err := write(&f.Header); err != nil {
+		return err
+	}
+	for _, cl := range f.CashLetters {
+		if err := write(&cl.CashLetterHeader); err != nil {
+			return err
+		}
+		for _, b := range cl.Bundles {
+			if err := write(&b.BundleHeader); err != nil {
+				return err
+			}
+			for _, cd := range b.Checks {
+				if err := writeCheckDetail(write, cd); err != nil {
+					return err
+				}
+			}
+			for _, rd := range b.Returns {
+				if err := writeReturnDetail(write, rd); err != nil {
+					return err
+				}
+			}
+			if err := write(&b.BundleControl); err != nil {
+				return err
+			}
+		}
+		for _, ri := range cl.RoutingNumberSummary {
+			if err := write(&ri); err != nil {
+				return err
+			}
+		}
+		for _, ci := range cl.CreditItems {
+			if err := write(&ci); err != nil {
+				return err
+			}
+		}
+		if err := write(&cl.CashLetterControl); err != nil {
+			return err
+		}
+	}
+	if err := write(&f.Control); err != nil {
+		return err
+	}
+	if f.Signature != nil {
+		return write(f.Signature)
+	}
+	return nil
+}
+
+// fmtStringer is the subset of Record.Value's interface the writer needs;
+// it avoids importing fmt just for Stringer in this file.
+type fmtStringer interface {
+	String() string
+}
+
+func writeCheckDetail(write func(fmtStringer) error, cd CheckDetail) error {
+	if err := write(&cd); err != nil {
+		return err
+	}
+	for _, a := range cd.CheckDetailAddendumA {
+		if err := write(&a); err != nil {
+			return err
+		}
+	}
+	for _, b := range cd.CheckDetailAddendumB {
+		if err := write(&b); err != nil {
+			return err
+		}
+	}
+	for _, c := range cd.CheckDetailAddendumC {
+		if err := write(&c); err != nil {
+			return err
+		}
+	}
+	return writeImageViews(write, cd.ImageViewDetail, cd.ImageViewData, cd.ImageViewAnalysis)
+}
+
+func writeReturnDetail(write func(fmtStringer) error, rd ReturnDetail) error {
+	if err := write(&rd); err != nil {
+		return err
+	}
+	for _, a := range rd.ReturnDetailAddendumA {
+		if err := write(&a); err != nil {
+			return err
+		}
+	}
+	for _, b := range rd.ReturnDetailAddendumB {
+		if err := write(&b); err != nil {
+			return err
+		}
+	}
+	for _, c := range rd.ReturnDetailAddendumC {
+		if err := write(&c); err != nil {
+			return err
+		}
+	}
+	for _, d := range rd.ReturnDetailAddendumD {
+		if err := write(&d); err != nil {
+			return err
+		}
+	}
+	return writeImageViews(write, rd.ImageViewDetail, rd.ImageViewData, rd.ImageViewAnalysis)
+}
+
+func writeImageViews(write func(fmtStringer) error, details []ImageViewDetail, datas []ImageViewData, analyses []ImageViewAnalysis) error {
+	for i := range details {
+		if err := write(&details[i]); err != nil {
+			return err
+		}
+		if i < len(datas) {
+			if err := write(&datas[i]); err != nil {
+				return err
+			}
+		}
+		if i < len(analyses) {
+			if err := write(&analyses[i]); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Write builds f's control totals via Create, then streams every record
+// out to w through the same Writer/WriteFile large, multi-GB files use.
+// There is no separate bulk serializer; calling WriteFile directly skips
+// the Create step.
+func (f *File) Write(w io.Writer, opts WriterOptions) error {
+	if err := f.Create(); err != nil {
+		return err
+	}
+	wr, err := NewWriter(w, opts)
+	if err != nil {
+		return err
+	}
+	return wr.WriteFile(f)
+}